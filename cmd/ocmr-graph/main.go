@@ -0,0 +1,38 @@
+// Command ocmr-graph serves the fake question generator over GraphQL: it wires a
+// snomed.DatabaseService into a graph.Resolver and serves the resulting schema (see
+// graph/schema.graphql) with a playground mounted at "/" for interactive exploration.
+//
+// It depends on graph/generated.go and graph/models_gen.go, which are gqlgen build output
+// produced by running `go generate ./...` from the repository root (see the directive atop
+// graph/graph.go) - they are not committed by hand, so this command cannot be built until
+// that generation step has been run with the gqlgen toolchain.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/playground"
+	"github.com/wardle/ocmr/graph"
+	"github.com/wardle/ocmr/snomed"
+)
+
+func main() {
+	databasePath := flag.String("db", "", "path to the SNOMED-CT database")
+	addr := flag.String("addr", ":8080", "address to listen on")
+	flag.Parse()
+
+	db, err := snomed.NewDatabaseService(*databasePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	resolver := &graph.Resolver{DB: db}
+	srv := handler.NewDefaultServer(graph.NewExecutableSchema(graph.Config{Resolvers: resolver}))
+
+	http.Handle("/", playground.Handler("ocmr", "/query"))
+	http.Handle("/query", srv)
+	log.Printf("listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
@@ -0,0 +1,24 @@
+// Command ocmr is the CLI entry point for generating fake question data from a SNOMED-CT
+// database: it wires up the snomed.DatabaseService and drives fake.GenerateFakeTruth.
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/wardle/ocmr/fake"
+	"github.com/wardle/ocmr/snomed"
+)
+
+func main() {
+	databasePath := flag.String("db", "", "path to the SNOMED-CT database")
+	n := flag.Int("n", -1, "number of diagnoses to generate fake questions for (-1 for all)")
+	failOnError := flag.Bool("ocmr.fail-on-error", false, "fail the generation run if any SeverityError diagnostic is emitted")
+	flag.Parse()
+
+	db, err := snomed.NewDatabaseService(*databasePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fake.GenerateFakeTruth(db, *n, *failOnError)
+}
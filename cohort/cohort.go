@@ -0,0 +1,142 @@
+// Package cohort partitions a generated stream of ocmr.Record values into clinically
+// meaningful cohorts - age band, sex, top-level SNOMED parent of the answer, and
+// finding-count bucket - and writes each cohort out as its own NDJSON file alongside a
+// manifest of counts, so downstream ML pipelines can do stratified train/test splits.
+package cohort
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/wardle/ocmr/ocmr"
+	"github.com/wardle/ocmr/snomed"
+)
+
+const (
+	paediatricMaxAge = 18 // below this age, a record falls into the "age-paediatric" cohort
+	elderlyMinAge    = 65 // at or above this age, a record falls into the "age-elderly" cohort
+
+	fewFindingsMax      = 3 // at or below this count, a record falls into "findings-few"
+	moderateFindingsMax = 7 // at or below this count (and above fewFindingsMax), "findings-moderate"
+)
+
+// Cohort is a named partition of generated records, defined by a Predicate over an
+// ocmr.Record. A record may belong to more than one Cohort, since the standard cohorts
+// built by NewSegmenter span independent axes (age, sex, diagnosis parent, finding count).
+type Cohort struct {
+	Label     string
+	Predicate func(*ocmr.Record) bool
+	Records   []*ocmr.Record
+}
+
+// Segmenter partitions a stream of records into a fixed set of Cohorts.
+type Segmenter struct {
+	Cohorts []*Cohort
+	total   int // number of records passed to Segment, for the manifest
+}
+
+// NewSegmenter builds the standard set of cohorts - age band, sex and finding-count bucket -
+// plus one additional cohort per entry in parentLabels, matching records whose cached
+// Answer Parents include that top-level SNOMED concept, e.g. {"cardiovascular": 49601007,
+// "neurological": 118940003}.
+func NewSegmenter(parentLabels map[string]snomed.Identifier) *Segmenter {
+	cohorts := []*Cohort{
+		{Label: "age-paediatric", Predicate: func(r *ocmr.Record) bool { return r.Age < paediatricMaxAge }},
+		{Label: "age-adult", Predicate: func(r *ocmr.Record) bool { return r.Age >= paediatricMaxAge && r.Age < elderlyMinAge }},
+		{Label: "age-elderly", Predicate: func(r *ocmr.Record) bool { return r.Age >= elderlyMinAge }},
+		{Label: "sex-male", Predicate: func(r *ocmr.Record) bool { return r.Sex == ocmr.Male }},
+		{Label: "sex-female", Predicate: func(r *ocmr.Record) bool { return r.Sex == ocmr.Female }},
+		{Label: "findings-few", Predicate: func(r *ocmr.Record) bool { return len(r.Findings) <= fewFindingsMax }},
+		{Label: "findings-moderate", Predicate: func(r *ocmr.Record) bool {
+			return len(r.Findings) > fewFindingsMax && len(r.Findings) <= moderateFindingsMax
+		}},
+		{Label: "findings-many", Predicate: func(r *ocmr.Record) bool { return len(r.Findings) > moderateFindingsMax }},
+	}
+	for label, parent := range parentLabels {
+		parent := parent
+		cohorts = append(cohorts, &Cohort{
+			Label:     "parent-" + label,
+			Predicate: func(r *ocmr.Record) bool { return hasParent(r, parent) },
+		})
+	}
+	return &Segmenter{Cohorts: cohorts}
+}
+
+func hasParent(record *ocmr.Record, parent snomed.Identifier) bool {
+	for _, p := range record.Parents {
+		if p.ConceptID == parent {
+			return true
+		}
+	}
+	return false
+}
+
+// Segment assigns each record to every cohort whose Predicate matches.
+func (s *Segmenter) Segment(records []*ocmr.Record) {
+	for _, record := range records {
+		s.total++
+		for _, cohort := range s.Cohorts {
+			if cohort.Predicate(record) {
+				cohort.Records = append(cohort.Records, record)
+			}
+		}
+	}
+}
+
+// Manifest summarises the record counts written for each cohort, keyed by cohort label.
+type Manifest struct {
+	Counts map[string]int `json:"counts"`
+	Total  int            `json:"total"` // total records segmented, regardless of cohort overlap
+}
+
+// WriteCohorts writes each non-empty cohort as its own NDJSON file (one record per line,
+// named "<label>.ndjson") under dir, plus a "manifest.json" recording the record count per
+// cohort and the total number of records segmented.
+func (s *Segmenter) WriteCohorts(dir string) (*Manifest, error) {
+	manifest := &Manifest{Counts: make(map[string]int, len(s.Cohorts)), Total: s.total}
+	for _, cohort := range s.Cohorts {
+		manifest.Counts[cohort.Label] = len(cohort.Records)
+		if len(cohort.Records) == 0 {
+			continue
+		}
+		if err := writeNDJSON(filepath.Join(dir, cohort.Label+".ndjson"), cohort.Records); err != nil {
+			return nil, fmt.Errorf("cohort: writing %s: %w", cohort.Label, err)
+		}
+	}
+	manifestFile, err := os.Create(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		return nil, err
+	}
+	defer manifestFile.Close()
+	encoder := json.NewEncoder(manifestFile)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func writeNDJSON(path string, records []*ocmr.Record) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	for _, record := range records {
+		b, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+		if err := w.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,72 @@
+package cohort
+
+import (
+	"testing"
+
+	"github.com/wardle/ocmr/ocmr"
+	"github.com/wardle/ocmr/snomed"
+)
+
+func TestNewSegmenterCohortPredicates(t *testing.T) {
+	cardiovascular := snomed.Identifier(49601007)
+	segmenter := NewSegmenter(map[string]snomed.Identifier{"cardiovascular": cardiovascular})
+
+	tests := []struct {
+		label  string
+		want   bool
+		record *ocmr.Record
+	}{
+		{label: "age-paediatric", want: true, record: &ocmr.Record{Age: 10}},
+		{label: "age-paediatric", want: false, record: &ocmr.Record{Age: 18}},
+		{label: "age-adult", want: true, record: &ocmr.Record{Age: 18}},
+		{label: "age-adult", want: false, record: &ocmr.Record{Age: 65}},
+		{label: "age-elderly", want: true, record: &ocmr.Record{Age: 65}},
+		{label: "sex-male", want: true, record: &ocmr.Record{Sex: ocmr.Male}},
+		{label: "sex-female", want: false, record: &ocmr.Record{Sex: ocmr.Male}},
+		{label: "findings-few", want: true, record: &ocmr.Record{Findings: make([]*ocmr.ClinicalFinding, 3)}},
+		{label: "findings-moderate", want: true, record: &ocmr.Record{Findings: make([]*ocmr.ClinicalFinding, 4)}},
+		{label: "findings-many", want: true, record: &ocmr.Record{Findings: make([]*ocmr.ClinicalFinding, 8)}},
+		{label: "parent-cardiovascular", want: true, record: &ocmr.Record{Parents: []*snomed.Concept{{ConceptID: cardiovascular}}}},
+		{label: "parent-cardiovascular", want: false, record: &ocmr.Record{Parents: []*snomed.Concept{{ConceptID: 1}}}},
+	}
+
+	byLabel := make(map[string]*Cohort, len(segmenter.Cohorts))
+	for _, cohort := range segmenter.Cohorts {
+		byLabel[cohort.Label] = cohort
+	}
+
+	for _, tt := range tests {
+		cohort, ok := byLabel[tt.label]
+		if !ok {
+			t.Fatalf("no cohort named %q", tt.label)
+		}
+		if got := cohort.Predicate(tt.record); got != tt.want {
+			t.Errorf("cohort %q predicate(%+v) = %v, want %v", tt.label, tt.record, got, tt.want)
+		}
+	}
+}
+
+func TestSegmentAssignsRecordsToEveryMatchingCohortAndTracksTotal(t *testing.T) {
+	segmenter := NewSegmenter(nil)
+	records := []*ocmr.Record{
+		{Age: 10, Sex: ocmr.Male, Findings: make([]*ocmr.ClinicalFinding, 1)},
+		{Age: 70, Sex: ocmr.Female, Findings: make([]*ocmr.ClinicalFinding, 8)},
+	}
+	segmenter.Segment(records)
+
+	if segmenter.total != len(records) {
+		t.Errorf("total = %d, want %d", segmenter.total, len(records))
+	}
+	for _, cohort := range segmenter.Cohorts {
+		switch cohort.Label {
+		case "age-paediatric", "sex-male", "findings-few":
+			if len(cohort.Records) != 1 || cohort.Records[0] != records[0] {
+				t.Errorf("cohort %q = %v, want just the first record", cohort.Label, cohort.Records)
+			}
+		case "age-elderly", "sex-female", "findings-many":
+			if len(cohort.Records) != 1 || cohort.Records[0] != records[1] {
+				t.Errorf("cohort %q = %v, want just the second record", cohort.Label, cohort.Records)
+			}
+		}
+	}
+}
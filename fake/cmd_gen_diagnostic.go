@@ -1,12 +1,77 @@
 package fake
 
 import (
+	"fmt"
+	"github.com/wardle/ocmr/ocmr"
 	"github.com/wardle/ocmr/snomed"
+	"strings"
 )
 
+// QuestionShape describes the kind of stem a generated question should pose, allowing a
+// single Truth to yield differently-flavoured questions: a diagnosis to make, an
+// investigation result to interpret, or a treatment to identify.
+type QuestionShape int
+
+// Possible values for QuestionShape
+const (
+	AskDiagnosis QuestionShape = iota
+	AskInvestigation
+	AskTreatment
+)
+
+func (qs QuestionShape) String() string {
+	switch qs {
+	case AskInvestigation:
+		return "AskInvestigation"
+	case AskTreatment:
+		return "AskTreatment"
+	default:
+		return "AskDiagnosis"
+	}
+}
+
+// Stem renders a single best answer question stem for record in this QuestionShape's
+// style, e.g. "what is the diagnosis?" vs "what does this investigation show?". It reports
+// false if record lacks the data this shape needs, e.g. AskTreatment with no recorded
+// medications.
+func (qs QuestionShape) Stem(record *ocmr.Record) (string, bool) {
+	findings := make([]string, 0, len(record.Findings))
+	for _, finding := range record.Findings {
+		findings = append(findings, finding.String())
+	}
+	scenario := strings.Join(findings, ", ")
+	switch qs {
+	case AskInvestigation:
+		if len(record.Investigations) == 0 {
+			return "", false
+		}
+		investigation := record.Investigations[0]
+		return fmt.Sprintf("A patient presents with %s. %s is performed. What does it show?", scenario, investigation.Concept.FullySpecifiedName), true
+	case AskTreatment:
+		if len(record.Medications) == 0 {
+			return "", false
+		}
+		return fmt.Sprintf("A patient presents with %s and is found to have %s. Which treatment is most appropriate?", scenario, record.Answer.FullySpecifiedName), true
+	default:
+		return fmt.Sprintf("A patient presents with %s. What is the most likely diagnosis?", scenario), true
+	}
+}
+
 // GenerateDiagnostic creates a set of fake questions from a fake source of truth
 // which essentially act as the model one would obtain from parsing lots of undergraduate
 // and postgraduate SBA (single best answer) questions.
 func GenerateDiagnostic(db *snomed.DatabaseService) {
-
+	truth, err := MyocardialInfarctionTruth(db)
+	checkError(err)
+	record, diagnostics := truth.ToQuestion(db)
+	for _, d := range diagnostics {
+		fmt.Println(d.String())
+	}
+	for _, shape := range []QuestionShape{AskDiagnosis, AskInvestigation, AskTreatment} {
+		stem, ok := shape.Stem(record)
+		if !ok {
+			continue
+		}
+		fmt.Println(stem)
+	}
 }
@@ -3,9 +3,11 @@ package fake
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/wardle/ocmr/nnmodel"
 	"github.com/wardle/ocmr/ocmr"
 	"github.com/wardle/ocmr/snomed"
 	"math/rand"
+	"os"
 	"strings"
 )
 
@@ -22,11 +24,36 @@ const (
 // While simply generating random problems for each diagnosis might be one approach, it is incorrect as
 // we have a clear subsumption IS-A hierarchy which can be used. As such, related diagnostic concepts
 // should share similar clinical problems in order to generate reasonable fake data.
-func GenerateFakeTruth(db *snomed.DatabaseService, n int) {
-	rootDiagnosis, err := db.FetchConcept(SctDiagnosisRoot)
+//
+// It prints every GenDiagnostic to stderr and panics if GenerateFakeTruthWithDiagnostics returns
+// an error; use GenerateFakeTruthWithDiagnostics directly to receive the diagnostics as values.
+func GenerateFakeTruth(db *snomed.DatabaseService, n int, failOnError bool) {
+	questions, diagnostics, err := GenerateFakeTruthWithDiagnostics(db, n, failOnError)
 	checkError(err)
-	allDiagnoses, err := db.FetchRecursiveChildren(rootDiagnosis)
+	for _, d := range diagnostics {
+		fmt.Fprintln(os.Stderr, d.String())
+	}
+	marshalled, err := json.MarshalIndent(questions, "", "  ")
 	checkError(err)
+	fmt.Print(string(marshalled))
+}
+
+// GenerateFakeTruthWithDiagnostics is a variant of GenerateFakeTruth that, rather than
+// panicking on the first problem, collects a GenDiagnostic for every low-quality or failed
+// truth or question and keeps going, returning the records generated despite them. If
+// failOnError is true, generation stops and returns a non-nil error as soon as any
+// SeverityError diagnostic is seen. A failure to fetch a finding's parents in
+// Problem.ToFinding still panics rather than producing a diagnostic.
+func GenerateFakeTruthWithDiagnostics(db *snomed.DatabaseService, n int, failOnError bool) ([]*ocmr.Record, []GenDiagnostic, error) {
+	var diagnostics []GenDiagnostic
+	rootDiagnosis, err := db.FetchConcept(SctDiagnosisRoot)
+	if err != nil {
+		return nil, diagnostics, err
+	}
+	allDiagnoses, err := db.FetchRecursiveChildren(rootDiagnosis)
+	if err != nil {
+		return nil, diagnostics, err
+	}
 	diagnoses := allDiagnoses
 	if n >= 0 {
 		diagnoses = make([]*snomed.Concept, n) // use the specified number to limit to (n) diagnoses
@@ -38,48 +65,155 @@ func GenerateFakeTruth(db *snomed.DatabaseService, n int) {
 	}
 	allTruth := make([]*Truth, 0, len(diagnoses)+1)
 	mi, err := MyocardialInfarctionTruth(db) // always prepend a "real" truth for illustrative purposes
-	checkError(err)
+	if err != nil {
+		return nil, diagnostics, err
+	}
 	allTruth = append(allTruth, mi)
 	for _, diag := range diagnoses {
-		truth, ok := generateTruth(db, diag)
+		truth, truthDiagnostics, ok := GenerateTruth(db, diag)
+		diagnostics = append(diagnostics, truthDiagnostics...)
+		if failOnError && hasError(truthDiagnostics) {
+			return nil, diagnostics, fmt.Errorf("ocmr: stopping, error generating a truth for %s", diag.FullySpecifiedName)
+		}
 		if ok {
 			allTruth = append(allTruth, truth)
 		}
 	}
-	prevalence := make(map[snomed.Identifier]float64, 0)
-	questions := make([]*ocmr.Record, 0)
+	model, evalSamples, sampleDiagnostics := trainPrevalenceModel(db, allTruth)
+	diagnostics = append(diagnostics, sampleDiagnostics...)
+	if failOnError && hasError(sampleDiagnostics) {
+		return nil, diagnostics, fmt.Errorf("ocmr: stopping, error generating a prevalence model sample")
+	}
+	questions := make([]*ocmr.Record, 0, len(allTruth))
 	for _, truth := range allTruth {
-		p := 5 + int(calculatePrevalence(db, prevalence, truth.Diagnosis)*10000)*n // we'll impute for this diagnosis based on prevalence
-		for i := 0; i < p; i++ {                                                   // generate number of questions commensurate with prevalence
-			question := truth.ToQuestion(db)
+		held := evalSamples[truth]
+		questions = append(questions, held)
+		p := 5 + int(prevalenceWeight(model, held)*10000)*n // we'll impute for this diagnosis based on prevalence
+		for j := 0; j < p; j++ { // generate number of questions commensurate with prevalence
+			question, questionDiagnostics := truth.ToQuestion(db)
+			diagnostics = append(diagnostics, questionDiagnostics...)
+			if failOnError && hasError(questionDiagnostics) {
+				return nil, diagnostics, fmt.Errorf("ocmr: stopping, error generating a question for %s", truth.Diagnosis.FullySpecifiedName)
+			}
 			questions = append(questions, question)
 		}
 	}
-	json, err := json.MarshalIndent(questions, "", "  ")
-	checkError(err)
-	fmt.Print(string(json))
+	return questions, diagnostics, nil
 }
 
-func generateTruth(db *snomed.DatabaseService, diagnosis *snomed.Concept) (*Truth, bool) {
-	symptoms, err := relatedBySiteForDiagnosis(db, diagnosis)
-	checkError(err)
-	totalSymptoms := len(symptoms)
-	if totalSymptoms > 0 {
-		numSymptoms := 1 + rand.Intn(min(30, totalSymptoms))
-		problems := make([]*Problem, numSymptoms)
-		parents, err := db.GetAllParents(diagnosis)
-		checkError(err)
-		for i := 0; i < numSymptoms; i++ {
-			symptom := symptoms[rand.Intn(totalSymptoms-1)]
-			problem := &Problem{symptom, randomDuration(), rand.Float64()}
-			problems[i] = problem
+// prevalenceModelEpochs is the number of training passes trainPrevalenceModel runs over its
+// training samples.
+const prevalenceModelEpochs = 20
+
+// prevalenceSamplesPerProblem is the number of candidate findings a truth needs for each
+// training sample trainPrevalenceModel draws beyond the first
+// (1 + len(truth.Problems)/prevalenceSamplesPerProblem samples in all): a diagnosis whose candidate-finding list (truth.Problems)
+// is larger contributes more training samples, giving buildVocab's frequency counts real
+// variance to rank on instead of every truth tying at a single sample.
+const prevalenceSamplesPerProblem = 5
+
+// trainPrevalenceModel builds an nnmodel.Model from training samples drawn per truth, then
+// draws one further held-out sample per truth that is never seen during training, so that
+// prevalenceWeight's later evaluation of it is a genuine measure of how well the model
+// generalises to that diagnosis rather than how well it memorised its training sample. This
+// replaces the subsumption-only prevalence heuristic this generator used to rely on: a
+// diagnosis whose held-out sample the model can confidently recognise has a distinctive
+// enough presentation to stand in as "common"; one it struggles with is treated as rarer.
+func trainPrevalenceModel(db *snomed.DatabaseService, allTruth []*Truth) (*nnmodel.Model, map[*Truth]*ocmr.Record, []GenDiagnostic) {
+	var diagnostics []GenDiagnostic
+	var trainSamples []*ocmr.Record
+	evalSamples := make(map[*Truth]*ocmr.Record, len(allTruth))
+	for _, truth := range allTruth {
+		trainCount := 1 + len(truth.Problems)/prevalenceSamplesPerProblem
+		for i := 0; i < trainCount; i++ {
+			sample, sampleDiagnostics := truth.ToQuestion(db)
+			diagnostics = append(diagnostics, sampleDiagnostics...)
+			trainSamples = append(trainSamples, sample)
 		}
-		meanAge := randomAge()
-		sd := min(meanAge, 20)
-		truth := &Truth{diagnosis, parents, problems, randomSexBias(), meanAge, rand.Intn(sd)}
-		return truth, true
+		held, heldDiagnostics := truth.ToQuestion(db)
+		diagnostics = append(diagnostics, heldDiagnostics...)
+		evalSamples[truth] = held
 	}
-	return nil, false
+	model := nnmodel.New()
+	model.Train(trainSamples, prevalenceModelEpochs)
+	return model, evalSamples, diagnostics
+}
+
+// prevalenceWeight returns the model's predicted confidence in sample's own diagnosis.
+func prevalenceWeight(model *nnmodel.Model, sample *ocmr.Record) float64 {
+	if sample.Answer == nil {
+		return 0
+	}
+	for _, score := range model.Predict(sample) {
+		if score.Diagnosis == sample.Answer.ConceptID {
+			return score.Probability
+		}
+	}
+	return 0
+}
+
+// GenerateTruth builds a fake Truth for a single, arbitrary diagnosis concept, for callers
+// (such as the graph package's resolvers) that need a truth for a diagnosis chosen at
+// request time rather than as part of a full GenerateFakeTruth batch run. Any GenDiagnostic
+// warnings or errors encountered along the way are returned alongside the truth.
+func GenerateTruth(db *snomed.DatabaseService, diagnosis *snomed.Concept) (*Truth, []GenDiagnostic, bool) {
+	return generateTruth(db, diagnosis)
+}
+
+func generateTruth(db *snomed.DatabaseService, diagnosis *snomed.Concept) (*Truth, []GenDiagnostic, bool) {
+	symptoms, diagnostics, err := relatedBySiteForDiagnosis(db, diagnosis)
+	if err != nil {
+		diagnostics = append(diagnostics, GenDiagnostic{
+			Severity: SeverityError,
+			Concept:  diagnosis.ConceptID,
+			Summary:  "failed to derive candidate findings",
+			Detail:   err.Error(),
+		})
+		return nil, diagnostics, false
+	}
+	totalSymptoms := len(symptoms)
+	if totalSymptoms < minCandidateFindings {
+		diagnostics = append(diagnostics, GenDiagnostic{
+			Severity: SeverityWarning,
+			Concept:  diagnosis.ConceptID,
+			Summary:  "few candidate findings",
+			Detail:   fmt.Sprintf("%s yielded only %d candidate findings (wanted at least %d)", diagnosis.FullySpecifiedName, totalSymptoms, minCandidateFindings),
+		})
+	}
+	if totalSymptoms == 0 {
+		return nil, diagnostics, false
+	}
+	numSymptoms := 1 + rand.Intn(min(30, totalSymptoms))
+	problems := make([]*Problem, numSymptoms)
+	parents, err := db.GetAllParents(diagnosis)
+	if err != nil {
+		diagnostics = append(diagnostics, GenDiagnostic{
+			Severity: SeverityError,
+			Concept:  diagnosis.ConceptID,
+			Summary:  "failed to fetch parents",
+			Detail:   err.Error(),
+		})
+		return nil, diagnostics, false
+	}
+	for i := 0; i < numSymptoms; i++ {
+		symptom := symptoms[randIntn(totalSymptoms)]
+		problem := &Problem{symptom, randomDuration(), rand.Float64()}
+		problems[i] = problem
+	}
+	meanAge := randomAge()
+	sd := min(meanAge, 20)
+	truth := &Truth{diagnosis, parents, problems, nil, nil, randomSexBias(), meanAge, randIntn(sd)}
+	return truth, diagnostics, true
+}
+
+// randIntn is rand.Intn that tolerates n <= 0 by returning 0 instead of panicking, for the
+// case (a mean age of 0, giving an age standard deviation bound of 0) where the usual
+// rand.Intn(n) precondition n > 0 doesn't hold.
+func randIntn(n int) int {
+	if n <= 0 {
+		return 0
+	}
+	return rand.Intn(n)
 }
 
 func randomDuration() ocmr.Duration {
@@ -104,12 +238,14 @@ func checkError(err error) {
 // (figures make up) but of course, our data will be fake but at least relatively consistent as questions
 // will be generated from the same "fake truth" but with different combinations of problems.
 type Truth struct {
-	Diagnosis *snomed.Concept
-	Parents   []*snomed.Concept // convenience pointers to parents
-	Problems  []*Problem        // problems for this diagnosis
-	SexBias   SexBias           // does this disorder have a sex bias?
-	MeanAge   int               // mean age
-	StdDevAge int               // standard deviation for age for this disorder
+	Diagnosis      *snomed.Concept
+	Parents        []*snomed.Concept // convenience pointers to parents
+	Problems       []*Problem        // problems for this diagnosis
+	Treatments     []*Medication     // typical treatments for this diagnosis
+	Investigations []*Investigation  // typical investigations for this diagnosis
+	SexBias        SexBias           // does this disorder have a sex bias?
+	MeanAge        int               // mean age
+	StdDevAge      int               // standard deviation for age for this disorder
 }
 
 // SexBias limits disorders to a gender, if appropriate
@@ -152,14 +288,30 @@ func (ft Truth) String() string {
 	return fmt.Sprintf("%s: %s", ft.Diagnosis.FullySpecifiedName, strings.Join(problems, ", "))
 }
 
-// ToQuestion creates a fake question from a fake truth by choosing a random selection of the symptoms on offer.
-func (ft Truth) ToQuestion(db *snomed.DatabaseService) *ocmr.Record {
+// ToQuestion creates a fake question from a fake truth by choosing a random selection of the symptoms,
+// treatments and investigations on offer. A failure to fetch the diagnosis's parents is returned
+// as a GenDiagnostic alongside the record rather than panicking; a failure to fetch a finding's
+// parents in Problem.ToFinding still panics.
+func (ft Truth) ToQuestion(db *snomed.DatabaseService) (*ocmr.Record, []GenDiagnostic) {
+	var diagnostics []GenDiagnostic
 	findings := make([]*ocmr.ClinicalFinding, 0)
 	for _, problem := range ft.Problems {
 		if problem.Probability > rand.Float64() {
 			findings = append(findings, problem.ToFinding(db))
 		}
 	}
+	medications := make([]*ocmr.MedicationStatement, 0)
+	for _, treatment := range ft.Treatments {
+		if treatment.Probability > rand.Float64() {
+			medications = append(medications, treatment.ToMedicationStatement())
+		}
+	}
+	investigations := make([]*ocmr.DiagnosticReport, 0)
+	for _, investigation := range ft.Investigations {
+		if investigation.Probability > rand.Float64() {
+			investigations = append(investigations, investigation.ToDiagnosticReport())
+		}
+	}
 	age := randomAge()
 	if ft.MeanAge > 0 && ft.StdDevAge > 0 {
 		age = int(rand.NormFloat64()*float64(ft.StdDevAge) + float64(ft.MeanAge))
@@ -169,8 +321,16 @@ func (ft Truth) ToQuestion(db *snomed.DatabaseService) *ocmr.Record {
 	}
 	sex := ft.SexBias.RandomSex()
 	parents, err := db.GetAllParents(ft.Diagnosis)
-	checkError(err)
-	return &ocmr.Record{Age: age, Sex: sex, Findings: findings, Answer: ft.Diagnosis, Parents: parents}
+	if err != nil {
+		diagnostics = append(diagnostics, GenDiagnostic{
+			Severity: SeverityError,
+			Concept:  ft.Diagnosis.ConceptID,
+			Summary:  "failed to fetch parents",
+			Detail:   err.Error(),
+		})
+	}
+	record := &ocmr.Record{Age: age, Sex: sex, Findings: findings, Medications: medications, Investigations: investigations, Answer: ft.Diagnosis, Parents: parents}
+	return record, diagnostics
 }
 
 // Problem records a clinical finding or observation and its probability
@@ -192,12 +352,49 @@ func (fp Problem) ToFinding(db *snomed.DatabaseService) *ocmr.ClinicalFinding {
 	return &ocmr.ClinicalFinding{Concept: fp.Problem, Parents: parents, Duration: fp.Duration}
 }
 
+// Medication records a typical treatment and its probability for an owning Diagnosis.
+type Medication struct {
+	Medication  *snomed.Concept // the medication given
+	Dose        string          // e.g. "300mg"
+	Frequency   string          // e.g. "once, stat"
+	Probability float64         // probability this treatment is given for this condition
+}
+
+func (fm Medication) String() string {
+	return fmt.Sprintf("%s (%f%%)", fm.Medication.FullySpecifiedName, fm.Probability)
+}
+
+// ToMedicationStatement turns a fake medication from a fake truth into a medication statement
+func (fm Medication) ToMedicationStatement() *ocmr.MedicationStatement {
+	return &ocmr.MedicationStatement{Concept: fm.Medication, Dose: fm.Dose, Frequency: fm.Frequency}
+}
+
+// Investigation records a typical investigation, its modality and coded result, and its
+// probability for an owning Diagnosis.
+type Investigation struct {
+	Investigation *snomed.Concept // the investigation performed, e.g. "electrocardiographic procedure"
+	Modality      string          // e.g. "ECG", "MRI", "CT"
+	Result        *snomed.Concept // the coded result of the investigation
+	Probability   float64         // probability this investigation is performed, and yields Result, for this condition
+}
+
+func (fi Investigation) String() string {
+	return fmt.Sprintf("%s (%f%%)", fi.Investigation.FullySpecifiedName, fi.Probability)
+}
+
+// ToDiagnosticReport turns a fake investigation from a fake truth into a diagnostic report
+func (fi Investigation) ToDiagnosticReport() *ocmr.DiagnosticReport {
+	return &ocmr.DiagnosticReport{Concept: fi.Investigation, Modality: fi.Modality, Result: fi.Result}
+}
+
 // convenience structure to allow literal defined truth for demonstration purposes.
 type explicitTruth struct {
-	diagnosis snomed.Identifier
-	problems  []*explicitProblem
-	meanAge   int
-	stdDevAge int
+	diagnosis      snomed.Identifier
+	problems       []*explicitProblem
+	treatments     []*explicitMedication
+	investigations []*explicitInvestigation
+	meanAge        int
+	stdDevAge      int
 }
 
 // convenience structure to allow literal defined problem for demonstration purposes.
@@ -207,6 +404,22 @@ type explicitProblem struct {
 	probability float64
 }
 
+// convenience structure to allow literal defined treatment for demonstration purposes.
+type explicitMedication struct {
+	conceptID   snomed.Identifier
+	dose        string
+	frequency   string
+	probability float64
+}
+
+// convenience structure to allow literal defined investigation for demonstration purposes.
+type explicitInvestigation struct {
+	conceptID   snomed.Identifier // the investigation performed
+	modality    string
+	resultID    snomed.Identifier // the coded result
+	probability float64
+}
+
 // toFakeTruth converts a (usually literal defined) explicit truth into a fake truth
 func (et explicitTruth) toFakeTruth(db *snomed.DatabaseService) (*Truth, error) {
 	diagnosis, err := db.FetchConcept(int(et.diagnosis))
@@ -221,11 +434,27 @@ func (et explicitTruth) toFakeTruth(db *snomed.DatabaseService) (*Truth, error)
 		}
 		problems = append(problems, fp)
 	}
+	treatments := make([]*Medication, 0, len(et.treatments))
+	for _, t := range et.treatments {
+		fm, err := t.toFakeMedication(db)
+		if err != nil {
+			return nil, err
+		}
+		treatments = append(treatments, fm)
+	}
+	investigations := make([]*Investigation, 0, len(et.investigations))
+	for _, i := range et.investigations {
+		fi, err := i.toFakeInvestigation(db)
+		if err != nil {
+			return nil, err
+		}
+		investigations = append(investigations, fi)
+	}
 	parents, err := db.GetAllParents(diagnosis)
 	if err != nil {
 		return nil, err
 	}
-	return &Truth{diagnosis, parents, problems, NoSexBias, et.meanAge, et.stdDevAge}, nil
+	return &Truth{diagnosis, parents, problems, treatments, investigations, NoSexBias, et.meanAge, et.stdDevAge}, nil
 }
 
 // toFakeProblem converts a (usually literal defined) explicit problem into a fake problem
@@ -237,6 +466,28 @@ func (ep explicitProblem) toFakeProblem(db *snomed.DatabaseService) (*Problem, e
 	return &Problem{concept, ep.duration, ep.probability}, nil
 }
 
+// toFakeMedication converts a (usually literal defined) explicit medication into a fake medication
+func (em explicitMedication) toFakeMedication(db *snomed.DatabaseService) (*Medication, error) {
+	concept, err := db.FetchConcept(int(em.conceptID))
+	if err != nil {
+		return nil, err
+	}
+	return &Medication{concept, em.dose, em.frequency, em.probability}, nil
+}
+
+// toFakeInvestigation converts a (usually literal defined) explicit investigation into a fake investigation
+func (ei explicitInvestigation) toFakeInvestigation(db *snomed.DatabaseService) (*Investigation, error) {
+	investigation, err := db.FetchConcept(int(ei.conceptID))
+	if err != nil {
+		return nil, err
+	}
+	result, err := db.FetchConcept(int(ei.resultID))
+	if err != nil {
+		return nil, err
+	}
+	return &Investigation{investigation, ei.modality, result, ei.probability}, nil
+}
+
 var myocardialInfarction = &explicitTruth{22298006,
 	[]*explicitProblem{
 		&explicitProblem{29857009, ocmr.Acute, 0.95},  // chest pain
@@ -244,6 +495,12 @@ var myocardialInfarction = &explicitTruth{22298006,
 		&explicitProblem{415690000, ocmr.Acute, 0.80}, // sweating
 		&explicitProblem{426555006, ocmr.Acute, 0.55}, // paint ot jaw
 		&explicitProblem{76388001, ocmr.Acute, 0.60},  // ST elevation on ECG - this will inherently say "ECG abnormal"
+	},
+	[]*explicitMedication{
+		&explicitMedication{387458008, "300mg", "once, stat", 0.90}, // aspirin
+	},
+	[]*explicitInvestigation{
+		&explicitInvestigation{29303009, "ECG", 164931005, 0.80}, // electrocardiographic procedure: ST segment elevation
 	}, 60, 20}
 
 // MyocardialInfarctionTruth generates a truth for myocardial infarction for demonstration and testing purposes.
@@ -254,14 +511,33 @@ func MyocardialInfarctionTruth(db *snomed.DatabaseService) (*Truth, error) {
 // RelatedBySiteForDiagnosis is a hacky way of getting a relatively reasonable list of clinical
 // findings for any arbitrary diagnosis by walking the SNOMED-CT ontology by finding site and finding
 // clinical findings for that site. It isn't at all perfect, but might make it look authentic to a non-medic!
-func relatedBySiteForDiagnosis(dbs *snomed.DatabaseService, concept *snomed.Concept) ([]*snomed.Concept, error) {
+//
+// Because it is hacky, it reports a GenDiagnostic rather than silently returning a poor-quality
+// (or empty) result whenever a diagnosis has no finding site, or a finding site can't be
+// genericised to one of the high-level structures it groups by.
+func relatedBySiteForDiagnosis(dbs *snomed.DatabaseService, concept *snomed.Concept) ([]*snomed.Concept, []GenDiagnostic, error) {
+	var diagnostics []GenDiagnostic
 	sites, err := dbs.GetParentsOfKind(concept, snomed.FindingSite) // where is this disease?
 	if err != nil {
-		return nil, err
+		return nil, diagnostics, err
+	}
+	if len(sites) == 0 {
+		diagnostics = append(diagnostics, GenDiagnostic{
+			Severity: SeverityWarning,
+			Concept:  concept.ConceptID,
+			Summary:  "no finding site",
+			Detail:   fmt.Sprintf("GetParentsOfKind(FindingSite) returned nothing for %s; its symptom list will be empty", concept.FullySpecifiedName),
+		})
 	}
 	allSymptoms := make(map[snomed.Identifier]*snomed.Concept)
-	thoracic, err := dbs.FetchConcept(51185008)  // high-level structure
+	thoracic, err := dbs.FetchConcept(51185008) // high-level structure
+	if err != nil {
+		return nil, diagnostics, err
+	}
 	structures, err := dbs.GetSiblings(thoracic) // get similiar high-level structures
+	if err != nil {
+		return nil, diagnostics, err
+	}
 	structures = append(structures, thoracic)
 	structures2 := snomed.SliceToMap(structures)
 	genericSites := make([]*snomed.Concept, 0)
@@ -269,14 +545,24 @@ func relatedBySiteForDiagnosis(dbs *snomed.DatabaseService, concept *snomed.Conc
 		genericSite, ok := dbs.Genericise(site, structures2)
 		if ok {
 			genericSites = append(genericSites, genericSite)
+		} else {
+			diagnostics = append(diagnostics, GenDiagnostic{
+				Severity: SeverityWarning,
+				Concept:  site.ConceptID,
+				Summary:  "Genericise failed",
+				Detail:   fmt.Sprintf("could not generalise finding site %s (for %s) to a known high-level structure", site.FullySpecifiedName, concept.FullySpecifiedName),
+			})
 		}
 	}
 	for _, site := range genericSites {
-		allChildren, _ := dbs.FetchRecursiveChildren(site)
+		allChildren, err := dbs.FetchRecursiveChildren(site)
+		if err != nil {
+			return nil, diagnostics, err
+		}
 		for _, child := range allChildren {
 			symptoms, err := dbs.GetChildrenOfKind(child, snomed.FindingSite)
 			if err != nil {
-				return nil, err
+				return nil, diagnostics, err
 			}
 			for _, symptom := range symptoms {
 				if symptom.IsA(snomed.SctDisease) == false {
@@ -285,7 +571,7 @@ func relatedBySiteForDiagnosis(dbs *snomed.DatabaseService, concept *snomed.Conc
 			}
 		}
 	}
-	return snomed.MapToSlice(allSymptoms), nil
+	return snomed.MapToSlice(allSymptoms), diagnostics, nil
 }
 
 /*
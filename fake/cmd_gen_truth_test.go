@@ -0,0 +1,20 @@
+package fake
+
+import "testing"
+
+func TestRandIntnToleratesNonPositiveN(t *testing.T) {
+	for _, n := range []int{-1, 0} {
+		if got := randIntn(n); got != 0 {
+			t.Errorf("randIntn(%d) = %d, want 0", n, got)
+		}
+	}
+}
+
+func TestRandIntnStaysWithinRange(t *testing.T) {
+	const n = 3
+	for i := 0; i < 100; i++ {
+		if got := randIntn(n); got < 0 || got >= n {
+			t.Fatalf("randIntn(%d) = %d, want [0, %d)", n, got, n)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package fake
+
+import (
+	"fmt"
+
+	"github.com/wardle/ocmr/snomed"
+)
+
+// minCandidateFindings is the number of candidate findings below which a diagnosis generates
+// a "few candidate findings" warning: relatedBySiteForDiagnosis is hacky and often yields a
+// thin, low-quality symptom list, and a diagnosis this short of candidates will tend to
+// repeat the same one or two findings across every generated question.
+const minCandidateFindings = 3
+
+// Severity indicates how serious a GenDiagnostic is.
+type Severity int
+
+// Possible values for Severity
+const (
+	SeverityWarning Severity = iota
+	SeverityError
+)
+
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "Error"
+	}
+	return "Warning"
+}
+
+// GenDiagnostic records a problem encountered while generating a fake truth or question, in
+// the spirit of hcl2's Diagnostic/DiagnosticSeverity: generateTruth, relatedBySiteForDiagnosis
+// and Truth.ToQuestion append one of these rather than panicking or silently dropping data, so
+// that callers can decide whether a warning is tolerable or an error should stop the run.
+// Truth.ToQuestion still panics, via Problem.ToFinding, if a finding's parents can't be fetched.
+type GenDiagnostic struct {
+	Severity Severity
+	Concept  snomed.Identifier // the concept the diagnostic relates to, e.g. the diagnosis or finding site
+	Summary  string
+	Detail   string
+}
+
+func (d GenDiagnostic) String() string {
+	return fmt.Sprintf("%s: %s (concept %d): %s", d.Severity, d.Summary, d.Concept, d.Detail)
+}
+
+// hasError reports whether diagnostics contains at least one SeverityError.
+func hasError(diagnostics []GenDiagnostic) bool {
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package fake
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wardle/ocmr/snomed"
+)
+
+func TestHasError(t *testing.T) {
+	tests := []struct {
+		name        string
+		diagnostics []GenDiagnostic
+		want        bool
+	}{
+		{name: "empty", diagnostics: nil, want: false},
+		{name: "only warnings", diagnostics: []GenDiagnostic{{Severity: SeverityWarning}}, want: false},
+		{name: "contains an error", diagnostics: []GenDiagnostic{{Severity: SeverityWarning}, {Severity: SeverityError}}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasError(tt.diagnostics); got != tt.want {
+				t.Errorf("hasError(%v) = %v, want %v", tt.diagnostics, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenDiagnosticString(t *testing.T) {
+	d := GenDiagnostic{Severity: SeverityError, Concept: snomed.Identifier(123), Summary: "no finding site", Detail: "got nothing"}
+	got := d.String()
+	for _, want := range []string{"Error", "no finding site", "123", "got nothing"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("GenDiagnostic.String() = %q, want it to contain %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+// Package fhir converts generated ocmr.Record values into FHIR R4 transaction
+// Bundles (Patient, Condition and Observation resources) so that the fake
+// dataset produced by the generator can be loaded into any FHIR-compliant
+// clinical store.
+package fhir
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/wardle/ocmr/ocmr"
+	"github.com/wardle/ocmr/snomed"
+)
+
+// sctSystem is the FHIR coding system URI for SNOMED-CT.
+const sctSystem = "http://snomed.info/sct"
+
+// Bundle is a minimal FHIR R4 Bundle resource, sufficient to represent a
+// transaction containing a Patient and its associated Conditions and
+// Observations.
+type Bundle struct {
+	ResourceType string        `json:"resourceType"`
+	Type         string        `json:"type"`
+	Entry        []BundleEntry `json:"entry"`
+}
+
+// BundleEntry is a single resource within a transaction Bundle.
+type BundleEntry struct {
+	FullURL  string      `json:"fullUrl"`
+	Resource interface{} `json:"resource"`
+	Request  *Request    `json:"request,omitempty"`
+}
+
+// Request describes how a bundled resource should be applied by the server.
+type Request struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+}
+
+// CodeableConcept is a FHIR coded value with optional text.
+type CodeableConcept struct {
+	Coding []Coding `json:"coding,omitempty"`
+	Text   string   `json:"text,omitempty"`
+}
+
+// Coding identifies a single concept from a coding system.
+type Coding struct {
+	System  string `json:"system"`
+	Code    string `json:"code"`
+	Display string `json:"display,omitempty"`
+}
+
+// Reference points to another resource within the same Bundle.
+type Reference struct {
+	Reference string `json:"reference"`
+}
+
+// Patient is a minimal FHIR R4 Patient resource.
+type Patient struct {
+	ResourceType string `json:"resourceType"`
+	ID           string `json:"id"`
+	Gender       string `json:"gender"`
+	BirthDate    string `json:"birthDate,omitempty"`
+}
+
+// Condition is a minimal FHIR R4 Condition resource, used here to represent
+// the single best answer (correct diagnosis) of a Record.
+type Condition struct {
+	ResourceType string          `json:"resourceType"`
+	ID           string          `json:"id"`
+	Code         CodeableConcept `json:"code"`
+	Subject      Reference       `json:"subject"`
+}
+
+// Observation is a minimal FHIR R4 Observation resource, used here to
+// represent a single ClinicalFinding.
+type Observation struct {
+	ResourceType      string          `json:"resourceType"`
+	ID                string          `json:"id"`
+	Status            string          `json:"status"`
+	Code              CodeableConcept `json:"code"`
+	Subject           Reference       `json:"subject"`
+	EffectiveDateTime string          `json:"effectiveDateTime,omitempty"`
+}
+
+// MarshalFHIR converts a single ocmr.Record into a FHIR R4 transaction Bundle
+// containing a Patient, a Condition for the correct-answer diagnosis and one
+// Observation per clinical finding.
+func MarshalFHIR(record *ocmr.Record) ([]byte, error) {
+	if record == nil {
+		return nil, fmt.Errorf("fhir: cannot marshal a nil record")
+	}
+	if record.Answer == nil {
+		return nil, fmt.Errorf("fhir: record has no answer to code as a Condition")
+	}
+	const patientID = "patient-1"
+	entries := make([]BundleEntry, 0, 2+len(record.Findings))
+	entries = append(entries, BundleEntry{
+		FullURL:  "urn:uuid:" + patientID,
+		Resource: newPatient(patientID, record.Age, record.Sex),
+		Request:  &Request{Method: "POST", URL: "Patient"},
+	})
+	const conditionID = "condition-1"
+	entries = append(entries, BundleEntry{
+		FullURL:  "urn:uuid:" + conditionID,
+		Resource: newCondition(conditionID, patientID, record.Answer),
+		Request:  &Request{Method: "POST", URL: "Condition"},
+	})
+	for i, finding := range record.Findings {
+		obsID := fmt.Sprintf("observation-%d", i+1)
+		entries = append(entries, BundleEntry{
+			FullURL:  "urn:uuid:" + obsID,
+			Resource: newObservation(obsID, patientID, finding),
+			Request:  &Request{Method: "POST", URL: "Observation"},
+		})
+	}
+	return json.Marshal(Bundle{ResourceType: "Bundle", Type: "transaction", Entry: entries})
+}
+
+func newPatient(id string, age int, sex ocmr.Sex) Patient {
+	return Patient{
+		ResourceType: "Patient",
+		ID:           id,
+		Gender:       genderCode(sex),
+		BirthDate:    approximateBirthDate(age),
+	}
+}
+
+func genderCode(sex ocmr.Sex) string {
+	switch sex {
+	case ocmr.Male:
+		return "male"
+	case ocmr.Female:
+		return "female"
+	default:
+		return "unknown"
+	}
+}
+
+// approximateBirthDate derives a year-only birth date from an age in years,
+// as the generator does not model an exact date of birth.
+func approximateBirthDate(age int) string {
+	if age <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d", time.Now().Year()-age)
+}
+
+func newCondition(id, patientID string, diagnosis *snomed.Concept) Condition {
+	return Condition{
+		ResourceType: "Condition",
+		ID:           id,
+		Code:         conceptToCodeableConcept(diagnosis),
+		Subject:      Reference{Reference: "urn:uuid:" + patientID},
+	}
+}
+
+func newObservation(id, patientID string, finding *ocmr.ClinicalFinding) Observation {
+	return Observation{
+		ResourceType:      "Observation",
+		ID:                id,
+		Status:            "final",
+		Code:              conceptToCodeableConcept(finding.Concept),
+		Subject:           Reference{Reference: "urn:uuid:" + patientID},
+		EffectiveDateTime: onsetFromDuration(finding.Duration),
+	}
+}
+
+func conceptToCodeableConcept(concept *snomed.Concept) CodeableConcept {
+	return CodeableConcept{
+		Coding: []Coding{{
+			System:  sctSystem,
+			Code:    fmt.Sprintf("%d", concept.ConceptID),
+			Display: concept.FullySpecifiedName,
+		}},
+		Text: concept.FullySpecifiedName,
+	}
+}
+
+// onsetFromDuration derives a plausible effectiveDateTime from a Duration,
+// e.g. Acute implies onset within the last 7 days.
+func onsetFromDuration(duration ocmr.Duration) string {
+	now := time.Now()
+	switch duration {
+	case ocmr.Acute:
+		return now.AddDate(0, 0, -3).Format(time.RFC3339) // within 7 days
+	case ocmr.Subacute:
+		return now.AddDate(0, 0, -42).Format(time.RFC3339)
+	case ocmr.Chronic:
+		return now.AddDate(-5, 0, 0).Format(time.RFC3339)
+	case ocmr.Episodic:
+		return now.AddDate(-1, 0, 0).Format(time.RFC3339)
+	default:
+		return ""
+	}
+}
+
+// NDJSONWriter streams FHIR Bundles as newline-delimited JSON, one Bundle per
+// ocmr.Record, suitable for bulk loading into a FHIR-compliant clinical store
+// (e.g. a Cloud Healthcare FHIR store's import API).
+type NDJSONWriter struct {
+	w *bufio.Writer
+}
+
+// NewNDJSONWriter wraps w, buffering writes until Flush is called.
+func NewNDJSONWriter(w io.Writer) *NDJSONWriter {
+	return &NDJSONWriter{w: bufio.NewWriter(w)}
+}
+
+// Write encodes record as a FHIR Bundle and writes it as a single NDJSON line.
+func (nw *NDJSONWriter) Write(record *ocmr.Record) error {
+	b, err := MarshalFHIR(record)
+	if err != nil {
+		return err
+	}
+	if _, err := nw.w.Write(b); err != nil {
+		return err
+	}
+	return nw.w.WriteByte('\n')
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (nw *NDJSONWriter) Flush() error {
+	return nw.w.Flush()
+}
+
+// WriteBatch streams records to w as NDJSON FHIR Bundles, one per line.
+func WriteBatch(w io.Writer, records []*ocmr.Record) error {
+	nw := NewNDJSONWriter(w)
+	for _, record := range records {
+		if err := nw.Write(record); err != nil {
+			return err
+		}
+	}
+	return nw.Flush()
+}
@@ -0,0 +1,98 @@
+package fhir
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/wardle/ocmr/ocmr"
+	"github.com/wardle/ocmr/snomed"
+)
+
+func TestApproximateBirthDate(t *testing.T) {
+	tests := []struct {
+		age       int
+		wantBlank bool
+	}{
+		{age: -1, wantBlank: true},
+		{age: 0, wantBlank: true},
+		{age: 40, wantBlank: false},
+	}
+	for _, tt := range tests {
+		got := approximateBirthDate(tt.age)
+		if tt.wantBlank {
+			if got != "" {
+				t.Errorf("approximateBirthDate(%d) = %q, want \"\"", tt.age, got)
+			}
+			continue
+		}
+		wantYear := time.Now().Year() - tt.age
+		if got != strconv.Itoa(wantYear) {
+			t.Errorf("approximateBirthDate(%d) = %q, want %q", tt.age, got, strconv.Itoa(wantYear))
+		}
+	}
+}
+
+func TestOnsetFromDurationOrdersMoreRecentDurationsLater(t *testing.T) {
+	parse := func(t *testing.T, s string) time.Time {
+		t.Helper()
+		ts, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatalf("onsetFromDuration returned an unparseable time %q: %v", s, err)
+		}
+		return ts
+	}
+	acute := parse(t, onsetFromDuration(ocmr.Acute))
+	subacute := parse(t, onsetFromDuration(ocmr.Subacute))
+	chronic := parse(t, onsetFromDuration(ocmr.Chronic))
+	episodic := parse(t, onsetFromDuration(ocmr.Episodic))
+
+	if !acute.After(subacute) {
+		t.Errorf("expected Acute onset %v to be more recent than Subacute onset %v", acute, subacute)
+	}
+	if !subacute.After(episodic) {
+		t.Errorf("expected Subacute onset %v to be more recent than Episodic onset %v", subacute, episodic)
+	}
+	if !episodic.After(chronic) {
+		t.Errorf("expected Episodic onset %v to be more recent than Chronic onset %v", episodic, chronic)
+	}
+	if got := onsetFromDuration(ocmr.Unknown); got != "" {
+		t.Errorf("onsetFromDuration(Unknown) = %q, want \"\"", got)
+	}
+}
+
+func TestMarshalFHIR(t *testing.T) {
+	record := &ocmr.Record{
+		Age: 55,
+		Sex: ocmr.Male,
+		Findings: []*ocmr.ClinicalFinding{
+			{Concept: &snomed.Concept{ConceptID: 29857009, FullySpecifiedName: "Chest pain"}, Duration: ocmr.Acute},
+		},
+		Answer: &snomed.Concept{ConceptID: 22298006, FullySpecifiedName: "Myocardial infarction"},
+	}
+	b, err := MarshalFHIR(record)
+	if err != nil {
+		t.Fatalf("MarshalFHIR() error = %v", err)
+	}
+	var bundle Bundle
+	if err := json.Unmarshal(b, &bundle); err != nil {
+		t.Fatalf("MarshalFHIR() produced invalid JSON: %v", err)
+	}
+	if bundle.ResourceType != "Bundle" || bundle.Type != "transaction" {
+		t.Errorf("bundle = %+v, want a transaction Bundle", bundle)
+	}
+	wantEntries := 2 + len(record.Findings) // Patient + Condition + one Observation per finding
+	if len(bundle.Entry) != wantEntries {
+		t.Errorf("bundle has %d entries, want %d", len(bundle.Entry), wantEntries)
+	}
+}
+
+func TestMarshalFHIRRejectsIncompleteRecords(t *testing.T) {
+	if _, err := MarshalFHIR(nil); err == nil {
+		t.Error("MarshalFHIR(nil) returned no error")
+	}
+	if _, err := MarshalFHIR(&ocmr.Record{}); err == nil {
+		t.Error("MarshalFHIR(record with no Answer) returned no error")
+	}
+}
@@ -0,0 +1,236 @@
+// Package graph exposes the fake question generator over GraphQL (see schema.graphql and
+// gqlgen.yml for the gqlgen-generated server this package's resolvers plug into), so that
+// clients can build UIs over the generated truth/record corpus without reloading the whole
+// JSON dump. snomed.Concept, ocmr.ClinicalFinding, ocmr.Sex and ocmr.Duration are bound
+// directly as GraphQL model types; Sex and Duration reuse their existing MarshalJSON logic
+// as custom scalars.
+//
+// Running `go generate ./...` (see the directive below) invokes gqlgen against schema.graphql
+// and gqlgen.yml to produce generated.go (the executable schema NewExecutableSchema builds on)
+// and models_gen.go (the generated input/payload types); cmd/ocmr-graph wires the result into
+// an HTTP server. Those two generated files are build output, not hand-maintained, so they
+// aren't written by hand here - run the command below with the gqlgen toolchain to produce them.
+//go:generate go run github.com/99designs/gqlgen generate
+package graph
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/wardle/ocmr/fake"
+	"github.com/wardle/ocmr/ocmr"
+	"github.com/wardle/ocmr/snomed"
+)
+
+// Resolver is the root gqlgen resolver. Truths and records are generated on demand from the
+// SNOMED-CT database service rather than precomputed.
+type Resolver struct {
+	DB *snomed.DatabaseService
+}
+
+// Query returns the root query resolver.
+func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
+
+// Mutation returns the root mutation resolver.
+func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
+
+// QueryResolver resolves the Query type's fields.
+type QueryResolver interface {
+	Truth(ctx context.Context, diagnosisID string) (*fake.Truth, error)
+	Records(ctx context.Context, diagnosisID *string, ageMin, ageMax *int, sex *ocmr.Sex, findingIDs []string) ([]*ocmr.Record, error)
+	Search(ctx context.Context, finding string) ([]*fake.Truth, error)
+}
+
+// MutationResolver resolves the Mutation type's fields.
+type MutationResolver interface {
+	GenerateRecords(ctx context.Context, diagnosisID string, count int) ([]*ocmr.Record, error)
+}
+
+type queryResolver struct{ r *Resolver }
+type mutationResolver struct{ r *Resolver }
+
+func (q *queryResolver) Truth(ctx context.Context, diagnosisID string) (*fake.Truth, error) {
+	diagnosis, err := fetchConceptByID(q.r.DB, diagnosisID)
+	if err != nil {
+		return nil, err
+	}
+	truth, _, ok := fake.GenerateTruth(q.r.DB, diagnosis)
+	if !ok {
+		return nil, fmt.Errorf("graph: no truth could be generated for diagnosis %s", diagnosisID)
+	}
+	return truth, nil
+}
+
+func (q *queryResolver) Records(ctx context.Context, diagnosisID *string, ageMin, ageMax *int, sex *ocmr.Sex, findingIDs []string) ([]*ocmr.Record, error) {
+	truths, err := q.candidateTruths(diagnosisID)
+	if err != nil {
+		return nil, err
+	}
+	findingSet := make(map[string]bool, len(findingIDs))
+	for _, id := range findingIDs {
+		findingSet[id] = true
+	}
+	records := make([]*ocmr.Record, 0)
+	for _, truth := range truths {
+		record, _ := truth.ToQuestion(q.r.DB)
+		if matchesRecord(record, ageMin, ageMax, sex, findingSet) {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+func (q *queryResolver) Search(ctx context.Context, finding string) ([]*fake.Truth, error) {
+	truths, err := q.candidateTruths(nil)
+	if err != nil {
+		return nil, err
+	}
+	matches := make([]*fake.Truth, 0)
+	for _, truth := range truths {
+		for _, problem := range truth.Problems {
+			if conceptIDString(problem.Problem) == finding {
+				matches = append(matches, truth)
+				break
+			}
+		}
+	}
+	return matches, nil
+}
+
+func (m *mutationResolver) GenerateRecords(ctx context.Context, diagnosisID string, count int) ([]*ocmr.Record, error) {
+	diagnosis, err := fetchConceptByID(m.r.DB, diagnosisID)
+	if err != nil {
+		return nil, err
+	}
+	truth, _, ok := fake.GenerateTruth(m.r.DB, diagnosis)
+	if !ok {
+		return nil, fmt.Errorf("graph: no truth could be generated for diagnosis %s", diagnosisID)
+	}
+	records := make([]*ocmr.Record, 0, count)
+	for i := 0; i < count; i++ {
+		record, _ := truth.ToQuestion(m.r.DB)
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// candidateTruths generates a Truth for diagnosisID, or for every diagnosis under
+// fake.SctDiagnosisRoot when diagnosisID is nil.
+func (q *queryResolver) candidateTruths(diagnosisID *string) ([]*fake.Truth, error) {
+	if diagnosisID != nil {
+		diagnosis, err := fetchConceptByID(q.r.DB, *diagnosisID)
+		if err != nil {
+			return nil, err
+		}
+		truth, _, ok := fake.GenerateTruth(q.r.DB, diagnosis)
+		if !ok {
+			return nil, nil
+		}
+		return []*fake.Truth{truth}, nil
+	}
+	root, err := q.r.DB.FetchConcept(fake.SctDiagnosisRoot)
+	if err != nil {
+		return nil, err
+	}
+	diagnoses, err := q.r.DB.FetchRecursiveChildren(root)
+	if err != nil {
+		return nil, err
+	}
+	truths := make([]*fake.Truth, 0, len(diagnoses))
+	for _, diagnosis := range diagnoses {
+		if truth, _, ok := fake.GenerateTruth(q.r.DB, diagnosis); ok {
+			truths = append(truths, truth)
+		}
+	}
+	return truths, nil
+}
+
+func fetchConceptByID(db *snomed.DatabaseService, id string) (*snomed.Concept, error) {
+	conceptID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, fmt.Errorf("graph: invalid concept id %q: %w", id, err)
+	}
+	return db.FetchConcept(conceptID)
+}
+
+func matchesRecord(record *ocmr.Record, ageMin, ageMax *int, sex *ocmr.Sex, findingIDs map[string]bool) bool {
+	if ageMin != nil && record.Age < *ageMin {
+		return false
+	}
+	if ageMax != nil && record.Age > *ageMax {
+		return false
+	}
+	if sex != nil && record.Sex != *sex {
+		return false
+	}
+	if len(findingIDs) == 0 {
+		return true
+	}
+	for _, finding := range record.Findings {
+		if findingIDs[conceptIDString(finding.Concept)] {
+			return true
+		}
+	}
+	return false
+}
+
+func conceptIDString(concept *snomed.Concept) string {
+	return strconv.FormatInt(int64(concept.ConceptID), 10)
+}
+
+// MarshalSex adapts ocmr.Sex's existing MarshalJSON logic to gqlgen's custom scalar
+// marshaling, so clients see the same "male"/"female"/"unknown" values as the JSON dump.
+func MarshalSex(sex ocmr.Sex) graphql.Marshaler {
+	return graphql.WriterFunc(func(w io.Writer) {
+		b, _ := sex.MarshalJSON()
+		_, _ = w.Write(b)
+	})
+}
+
+// UnmarshalSex parses a GraphQL Sex scalar value back into an ocmr.Sex.
+func UnmarshalSex(v interface{}) (ocmr.Sex, error) {
+	s, ok := v.(string)
+	if !ok {
+		return 0, fmt.Errorf("graph: Sex must be a string")
+	}
+	switch s {
+	case "male":
+		return ocmr.Male, nil
+	case "female":
+		return ocmr.Female, nil
+	default:
+		return 0, fmt.Errorf("graph: unknown Sex %q", s)
+	}
+}
+
+// MarshalDuration adapts ocmr.Duration's existing MarshalJSON logic to gqlgen's custom
+// scalar marshaling.
+func MarshalDuration(duration ocmr.Duration) graphql.Marshaler {
+	return graphql.WriterFunc(func(w io.Writer) {
+		b, _ := duration.MarshalJSON()
+		_, _ = w.Write(b)
+	})
+}
+
+// UnmarshalDuration parses a GraphQL Duration scalar value back into an ocmr.Duration.
+func UnmarshalDuration(v interface{}) (ocmr.Duration, error) {
+	s, ok := v.(string)
+	if !ok {
+		return ocmr.Unknown, fmt.Errorf("graph: Duration must be a string")
+	}
+	switch s {
+	case "Acute":
+		return ocmr.Acute, nil
+	case "Subacute":
+		return ocmr.Subacute, nil
+	case "Chronic":
+		return ocmr.Chronic, nil
+	case "Episodic":
+		return ocmr.Episodic, nil
+	default:
+		return ocmr.Unknown, nil
+	}
+}
@@ -0,0 +1,423 @@
+// Package nnmodel learns P(diagnosis | findings, age, sex) from batches of
+// generated ocmr.Record, replacing the subsumption-only prevalence heuristic
+// used implicitly by fake.GenerateFakeTruth with a small trainable neural
+// model that can then score real cases.
+//
+// Findings are folded into a fixed-width hashed bag-of-concepts: each active
+// ClinicalFinding.Concept, and its cached Parents, sets a slot in the feature
+// vector so that IS-A generalisation carries over at prediction time, e.g.
+// training on "myocardial infarction" transfers to "acute coronary syndrome"
+// because both hash in their shared parent concepts.
+package nnmodel
+
+import (
+	"encoding/binary"
+	"encoding/gob"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+
+	"github.com/wardle/ocmr/ocmr"
+	"github.com/wardle/ocmr/snomed"
+)
+
+const (
+	// ConceptHashBits is the width of the hashed bag-of-concepts feature space (2^15 slots).
+	ConceptHashBits = 15
+	conceptHashSize = 1 << ConceptHashBits
+
+	ageFeatureIdx    = conceptHashSize
+	maleFeatureIdx   = conceptHashSize + 1
+	femaleFeatureIdx = conceptHashSize + 2
+	featureSize      = conceptHashSize + 3
+
+	hidden1Size = 64
+	hidden2Size = 32
+
+	// MaxDiagnoses caps the number of output classes: only the MaxDiagnoses most
+	// frequently seen diagnoses during training are kept in the softmax output.
+	MaxDiagnoses = 64
+
+	learningRate = 0.01
+	batchSize    = 32
+)
+
+// DiagnosisScore is a predicted probability for a single diagnosis.
+type DiagnosisScore struct {
+	Diagnosis   snomed.Identifier
+	Probability float64
+}
+
+// Model is a two-hidden-layer MLP (ReLU, softmax output) mapping a hashed
+// bag-of-concepts feature vector to a probability distribution over the most
+// common diagnoses seen during training.
+type Model struct {
+	Vocab []snomed.Identifier // output index -> diagnosis concept id
+
+	W1, B1 []float64 // featureSize x hidden1Size, hidden1Size
+	W2, B2 []float64 // hidden1Size x hidden2Size, hidden2Size
+	W3, B3 []float64 // hidden2Size x len(Vocab), len(Vocab)
+
+	vocabIndex map[snomed.Identifier]int // diagnosis concept id -> output index; rebuilt after Load
+}
+
+// New creates an untrained Model. Call Train before Predict.
+func New() *Model {
+	return &Model{}
+}
+
+// Train fits the model to predict each record's Answer from its Findings, Age and Sex,
+// running epochs passes of mini-batch stochastic gradient descent over a cross-entropy
+// loss. The first call to Train fixes the diagnosis vocabulary (and so the output
+// dimension); subsequent calls continue training the existing vocabulary and weights.
+func (m *Model) Train(records []*ocmr.Record, epochs int) {
+	m.buildVocab(records)
+	outputSize := len(m.Vocab)
+	if outputSize == 0 {
+		return
+	}
+	if m.W1 == nil {
+		m.W1 = newMatrix(featureSize, hidden1Size)
+		m.B1 = make([]float64, hidden1Size)
+		m.W2 = newMatrix(hidden1Size, hidden2Size)
+		m.B2 = make([]float64, hidden2Size)
+		m.W3 = newMatrix(hidden2Size, outputSize)
+		m.B3 = make([]float64, outputSize)
+	}
+	trainable := make([]*ocmr.Record, 0, len(records))
+	for _, record := range records {
+		if record.Answer == nil {
+			continue
+		}
+		if _, ok := m.vocabIndex[record.Answer.ConceptID]; ok {
+			trainable = append(trainable, record)
+		}
+	}
+	for epoch := 0; epoch < epochs; epoch++ {
+		rand.Shuffle(len(trainable), func(i, j int) { trainable[i], trainable[j] = trainable[j], trainable[i] })
+		for start := 0; start < len(trainable); start += batchSize {
+			m.trainBatch(trainable[start:min(start+batchSize, len(trainable))])
+		}
+	}
+}
+
+// Predict scores record against every diagnosis in the model's vocabulary, returning one
+// DiagnosisScore per class sorted by descending probability.
+func (m *Model) Predict(record *ocmr.Record) []DiagnosisScore {
+	if len(m.Vocab) == 0 {
+		return nil
+	}
+	features := featurize(record)
+	z1 := forwardLayer1(features, m.W1, m.B1)
+	a1 := reluVec(z1)
+	z2 := matVec(a1, m.W2, m.B2, hidden1Size, hidden2Size)
+	a2 := reluVec(z2)
+	z3 := matVec(a2, m.W3, m.B3, hidden2Size, len(m.Vocab))
+	probs := softmax(z3)
+
+	scores := make([]DiagnosisScore, len(m.Vocab))
+	for i, id := range m.Vocab {
+		scores[i] = DiagnosisScore{Diagnosis: id, Probability: probs[i]}
+	}
+	sort.Slice(scores, func(i, j int) bool { return scores[i].Probability > scores[j].Probability })
+	return scores
+}
+
+// Save persists the model, including its learned weights and diagnosis vocabulary, to path
+// using gob encoding.
+func (m *Model) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return gob.NewEncoder(f).Encode(m)
+}
+
+// Load restores a model previously written by Save.
+func Load(path string) (*Model, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	m := &Model{}
+	if err := gob.NewDecoder(f).Decode(m); err != nil {
+		return nil, err
+	}
+	m.rebuildVocabIndex()
+	return m, nil
+}
+
+// buildVocab fixes the output vocabulary to the MaxDiagnoses most frequent answers seen in
+// records, breaking frequency ties by concept id for determinism. It is a no-op once a
+// vocabulary already exists, so repeated Train calls keep predicting over the same classes.
+func (m *Model) buildVocab(records []*ocmr.Record) {
+	if len(m.Vocab) > 0 {
+		return
+	}
+	counts := make(map[snomed.Identifier]int)
+	for _, record := range records {
+		if record.Answer == nil {
+			continue
+		}
+		counts[record.Answer.ConceptID]++
+	}
+	ids := make([]snomed.Identifier, 0, len(counts))
+	for id := range counts {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if counts[ids[i]] != counts[ids[j]] {
+			return counts[ids[i]] > counts[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	if len(ids) > MaxDiagnoses {
+		ids = ids[:MaxDiagnoses]
+	}
+	m.Vocab = ids
+	m.rebuildVocabIndex()
+}
+
+func (m *Model) rebuildVocabIndex() {
+	m.vocabIndex = make(map[snomed.Identifier]int, len(m.Vocab))
+	for i, id := range m.Vocab {
+		m.vocabIndex[id] = i
+	}
+}
+
+// trainBatch accumulates gradients for one mini-batch and applies a single averaged SGD
+// update to every layer.
+func (m *Model) trainBatch(batch []*ocmr.Record) {
+	outputSize := len(m.Vocab)
+	gW1 := make(map[int][]float64) // sparse: only features active in this batch
+	gB1 := make([]float64, hidden1Size)
+	gW2 := make([]float64, hidden1Size*hidden2Size)
+	gB2 := make([]float64, hidden2Size)
+	gW3 := make([]float64, hidden2Size*outputSize)
+	gB3 := make([]float64, outputSize)
+
+	for _, record := range batch {
+		target := m.vocabIndex[record.Answer.ConceptID]
+		features := featurize(record)
+
+		z1 := forwardLayer1(features, m.W1, m.B1)
+		a1 := reluVec(z1)
+		z2 := matVec(a1, m.W2, m.B2, hidden1Size, hidden2Size)
+		a2 := reluVec(z2)
+		z3 := matVec(a2, m.W3, m.B3, hidden2Size, outputSize)
+		probs := softmax(z3)
+
+		// Gradient of cross-entropy loss w.r.t. the softmax logits is (probs - onehot(target)).
+		dz3 := append([]float64(nil), probs...)
+		dz3[target] -= 1
+
+		for h2 := 0; h2 < hidden2Size; h2++ {
+			for k := 0; k < outputSize; k++ {
+				gW3[h2*outputSize+k] += a2[h2] * dz3[k]
+			}
+		}
+		for k := 0; k < outputSize; k++ {
+			gB3[k] += dz3[k]
+		}
+
+		da2 := make([]float64, hidden2Size)
+		for h2 := 0; h2 < hidden2Size; h2++ {
+			var sum float64
+			for k := 0; k < outputSize; k++ {
+				sum += m.W3[h2*outputSize+k] * dz3[k]
+			}
+			da2[h2] = sum
+		}
+		dz2 := reluBackward(z2, da2)
+
+		for h1 := 0; h1 < hidden1Size; h1++ {
+			for h2 := 0; h2 < hidden2Size; h2++ {
+				gW2[h1*hidden2Size+h2] += a1[h1] * dz2[h2]
+			}
+		}
+		for h2 := 0; h2 < hidden2Size; h2++ {
+			gB2[h2] += dz2[h2]
+		}
+
+		da1 := make([]float64, hidden1Size)
+		for h1 := 0; h1 < hidden1Size; h1++ {
+			var sum float64
+			for h2 := 0; h2 < hidden2Size; h2++ {
+				sum += m.W2[h1*hidden2Size+h2] * dz2[h2]
+			}
+			da1[h1] = sum
+		}
+		dz1 := reluBackward(z1, da1)
+
+		for _, f := range features {
+			grad, ok := gW1[f.index]
+			if !ok {
+				grad = make([]float64, hidden1Size)
+				gW1[f.index] = grad
+			}
+			for h := 0; h < hidden1Size; h++ {
+				grad[h] += f.value * dz1[h]
+			}
+		}
+		for h := 0; h < hidden1Size; h++ {
+			gB1[h] += dz1[h]
+		}
+	}
+
+	n := float64(len(batch))
+	for idx, grad := range gW1 {
+		row := idx * hidden1Size
+		for h := 0; h < hidden1Size; h++ {
+			m.W1[row+h] -= learningRate * grad[h] / n
+		}
+	}
+	for h := 0; h < hidden1Size; h++ {
+		m.B1[h] -= learningRate * gB1[h] / n
+	}
+	for i := range m.W2 {
+		m.W2[i] -= learningRate * gW2[i] / n
+	}
+	for h2 := 0; h2 < hidden2Size; h2++ {
+		m.B2[h2] -= learningRate * gB2[h2] / n
+	}
+	for i := range m.W3 {
+		m.W3[i] -= learningRate * gW3[i] / n
+	}
+	for k := 0; k < outputSize; k++ {
+		m.B3[k] -= learningRate * gB3[k] / n
+	}
+}
+
+// activeFeature is one non-zero slot of the (otherwise sparse) input feature vector.
+type activeFeature struct {
+	index int
+	value float64
+}
+
+// featurize builds the hashed bag-of-concepts feature vector for a record: every finding
+// concept and its cached parents set a slot to 1, followed by normalised age and a one-hot
+// sex. Folding parents in alongside the finding itself is what lets IS-A generalisation
+// transfer at prediction time.
+func featurize(record *ocmr.Record) []activeFeature {
+	features := make([]activeFeature, 0, 2*len(record.Findings)+3)
+	seen := make(map[int]bool)
+	add := func(idx int, val float64) {
+		if seen[idx] {
+			return
+		}
+		seen[idx] = true
+		features = append(features, activeFeature{idx, val})
+	}
+	for _, finding := range record.Findings {
+		if finding.Concept != nil {
+			add(hashConcept(finding.Concept.ConceptID), 1)
+		}
+		for _, parent := range finding.Parents {
+			if parent != nil {
+				add(hashConcept(parent.ConceptID), 1)
+			}
+		}
+	}
+	add(ageFeatureIdx, normalizeAge(record.Age))
+	switch record.Sex {
+	case ocmr.Male:
+		add(maleFeatureIdx, 1)
+	case ocmr.Female:
+		add(femaleFeatureIdx, 1)
+	}
+	return features
+}
+
+func hashConcept(id snomed.Identifier) int {
+	h := fnv.New64a()
+	_ = binary.Write(h, binary.LittleEndian, int64(id))
+	return int(h.Sum64() % conceptHashSize)
+}
+
+func normalizeAge(age int) float64 {
+	return float64(age) / 100.0
+}
+
+func newMatrix(rows, cols int) []float64 {
+	m := make([]float64, rows*cols)
+	scale := math.Sqrt(2.0 / float64(rows))
+	for i := range m {
+		m[i] = rand.NormFloat64() * scale
+	}
+	return m
+}
+
+// forwardLayer1 computes the first hidden layer's pre-activation directly from the sparse
+// feature list, avoiding an O(featureSize) pass over mostly-zero inputs.
+func forwardLayer1(features []activeFeature, W1, B1 []float64) []float64 {
+	z1 := append([]float64(nil), B1...)
+	for _, f := range features {
+		row := f.index * hidden1Size
+		for h := 0; h < hidden1Size; h++ {
+			z1[h] += f.value * W1[row+h]
+		}
+	}
+	return z1
+}
+
+// matVec computes x*W + B for a dense [rows x cols] weight matrix stored row-major.
+func matVec(x, W, B []float64, rows, cols int) []float64 {
+	out := append([]float64(nil), B...)
+	for i := 0; i < rows; i++ {
+		xi := x[i]
+		if xi == 0 {
+			continue
+		}
+		row := i * cols
+		for j := 0; j < cols; j++ {
+			out[j] += xi * W[row+j]
+		}
+	}
+	return out
+}
+
+func reluVec(z []float64) []float64 {
+	out := make([]float64, len(z))
+	for i, v := range z {
+		if v > 0 {
+			out[i] = v
+		}
+	}
+	return out
+}
+
+// reluBackward applies the ReLU derivative (gating upstream gradients by whether the
+// corresponding pre-activation z was positive).
+func reluBackward(z, upstream []float64) []float64 {
+	out := make([]float64, len(z))
+	for i, v := range z {
+		if v > 0 {
+			out[i] = upstream[i]
+		}
+	}
+	return out
+}
+
+func softmax(z []float64) []float64 {
+	maxZ := z[0]
+	for _, v := range z[1:] {
+		if v > maxZ {
+			maxZ = v
+		}
+	}
+	out := make([]float64, len(z))
+	var sum float64
+	for i, v := range z {
+		e := math.Exp(v - maxZ)
+		out[i] = e
+		sum += e
+	}
+	for i := range out {
+		out[i] /= sum
+	}
+	return out
+}
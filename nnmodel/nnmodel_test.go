@@ -0,0 +1,91 @@
+package nnmodel
+
+import (
+	"math"
+	"testing"
+
+	"github.com/wardle/ocmr/ocmr"
+	"github.com/wardle/ocmr/snomed"
+)
+
+func TestFeaturizeDeduplicatesSharedConceptsAndSetsAgeSex(t *testing.T) {
+	shared := &snomed.Concept{ConceptID: 100}
+	record := &ocmr.Record{
+		Age: 40,
+		Sex: ocmr.Male,
+		Findings: []*ocmr.ClinicalFinding{
+			{Concept: &snomed.Concept{ConceptID: 1}, Parents: []*snomed.Concept{shared}},
+			{Concept: &snomed.Concept{ConceptID: 2}, Parents: []*snomed.Concept{shared}},
+		},
+	}
+	features := featurize(record)
+
+	seen := make(map[int]bool)
+	for _, f := range features {
+		if seen[f.index] {
+			t.Fatalf("featurize produced duplicate slot %d", f.index)
+		}
+		seen[f.index] = true
+	}
+	if !seen[ageFeatureIdx] {
+		t.Error("expected an age feature to be set")
+	}
+	if !seen[maleFeatureIdx] || seen[femaleFeatureIdx] {
+		t.Error("expected only the male sex feature to be set")
+	}
+	// the shared parent concept should only contribute a single slot despite appearing twice
+	if len(features) != 5 {
+		t.Errorf("featurize() returned %d features, want 5 (2 finding concepts + 1 shared parent + age + male, deduplicated)", len(features))
+	}
+}
+
+func TestSoftmaxSumsToOneAndPrefersLargerLogit(t *testing.T) {
+	probs := softmax([]float64{1, 2, 3})
+	var sum float64
+	for _, p := range probs {
+		sum += p
+	}
+	if math.Abs(sum-1) > 1e-9 {
+		t.Errorf("softmax probabilities sum to %v, want 1", sum)
+	}
+	if probs[2] <= probs[1] || probs[1] <= probs[0] {
+		t.Errorf("softmax(%v) = %v, want monotonically increasing with the logits", []float64{1, 2, 3}, probs)
+	}
+}
+
+func TestTrainBatchReducesLossForASingleExample(t *testing.T) {
+	m := &Model{Vocab: []snomed.Identifier{1, 2}}
+	m.rebuildVocabIndex()
+	m.W1 = newMatrix(featureSize, hidden1Size)
+	m.B1 = make([]float64, hidden1Size)
+	m.W2 = newMatrix(hidden1Size, hidden2Size)
+	m.B2 = make([]float64, hidden2Size)
+	m.W3 = newMatrix(hidden2Size, len(m.Vocab))
+	m.B3 = make([]float64, len(m.Vocab))
+
+	record := &ocmr.Record{
+		Age:      50,
+		Sex:      ocmr.Female,
+		Answer:   &snomed.Concept{ConceptID: 1},
+		Findings: []*ocmr.ClinicalFinding{{Concept: &snomed.Concept{ConceptID: 42}}},
+	}
+	scoreBefore := prevalenceWeightForTest(m, record)
+	for i := 0; i < 50; i++ {
+		m.trainBatch([]*ocmr.Record{record})
+	}
+	scoreAfter := prevalenceWeightForTest(m, record)
+	if scoreAfter <= scoreBefore {
+		t.Errorf("trainBatch did not increase predicted probability of the true class: before=%v after=%v", scoreBefore, scoreAfter)
+	}
+}
+
+// prevalenceWeightForTest mirrors fake.prevalenceWeight's lookup without depending on that
+// package, to keep this test scoped to nnmodel's own behaviour.
+func prevalenceWeightForTest(m *Model, record *ocmr.Record) float64 {
+	for _, score := range m.Predict(record) {
+		if score.Diagnosis == record.Answer.ConceptID {
+			return score.Probability
+		}
+	}
+	return 0
+}
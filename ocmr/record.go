@@ -9,11 +9,13 @@ import (
 
 // Record is made up of a scenario containing findings and one single best answer.
 type Record struct {
-	Age      int
-	Sex      Sex
-	Findings []*ClinicalFinding // a list of clinical findings derived from the scenario (stem)
-	Answer   *snomed.Concept    // the single best answer
-	Parents  []*snomed.Concept  // cache parents of correct answer for ease-of-use in subsequent processing
+	Age            int
+	Sex            Sex
+	Findings       []*ClinicalFinding     // a list of clinical findings derived from the scenario (stem)
+	Medications    []*MedicationStatement // medications relevant to the scenario, e.g. a causative or a treatment drug
+	Investigations []*DiagnosticReport    // investigations relevant to the scenario, e.g. an ECG or MRI and its result
+	Answer         *snomed.Concept        // the single best answer
+	Parents        []*snomed.Concept      // cache parents of correct answer for ease-of-use in subsequent processing
 }
 
 func (q Record) String() string {
@@ -90,3 +92,27 @@ type ClinicalFinding struct {
 func (cf ClinicalFinding) String() string {
 	return cf.Duration.String() + " " + cf.Concept.FullySpecifiedName
 }
+
+// MedicationStatement combines a medication SNOMED-CT concept with its dose and frequency
+// e.g. aspirin 300mg once, stat
+type MedicationStatement struct {
+	Concept   *snomed.Concept
+	Dose      string
+	Frequency string
+}
+
+func (ms MedicationStatement) String() string {
+	return fmt.Sprintf("%s %s %s", ms.Concept.FullySpecifiedName, ms.Dose, ms.Frequency)
+}
+
+// DiagnosticReport combines an investigation SNOMED-CT concept, its modality (e.g. MRI, CT,
+// ECG) and a coded result, e.g. "electrocardiographic procedure (ECG): ST segment elevation"
+type DiagnosticReport struct {
+	Concept  *snomed.Concept
+	Modality string
+	Result   *snomed.Concept
+}
+
+func (dr DiagnosticReport) String() string {
+	return fmt.Sprintf("%s (%s): %s", dr.Concept.FullySpecifiedName, dr.Modality, dr.Result.FullySpecifiedName)
+}